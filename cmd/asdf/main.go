@@ -0,0 +1,52 @@
+// Command asdf is the Go entry point for asdf's configuration tooling. It
+// currently only exposes the `config check` path described in the
+// internal/config package; the rest of asdf's commands remain implemented
+// as the shell scripts under bin/.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/neuroradiology/asdf/internal/config"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 2 && args[0] == "config" && args[1] == "check" {
+		return runConfigCheck()
+	}
+	return fmt.Errorf("usage: asdf config check")
+}
+
+// runConfigCheck loads the merged asdfrc configuration and prints every
+// Diagnostic it reports. It returns an error (and so a non-zero exit code
+// from main) when any diagnostic is an error rather than a warning, so
+// scripts can rely on the exit code instead of parsing the output.
+func runConfigCheck() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	diagnostics := cfg.Validate()
+	if len(diagnostics) == 0 {
+		fmt.Println("asdfrc: no problems found")
+		return nil
+	}
+
+	fmt.Println(config.FormatDiagnostics(diagnostics))
+
+	for _, d := range diagnostics {
+		if d.Severity == config.SeverityError {
+			return fmt.Errorf("asdfrc: %d problem(s) found", len(diagnostics))
+		}
+	}
+	return nil
+}
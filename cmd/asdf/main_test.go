@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("Rejects an unknown command", func(t *testing.T) {
+		assert.NotNil(t, run(nil))
+		assert.NotNil(t, run([]string{"plugin", "list"}))
+	})
+
+	t.Run("config check succeeds against a clean asdfrc", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("ASDF_SYSTEM_CONFIG_FILE", filepath.Join(home, "nonexistent-system-asdfrc"))
+		t.Setenv("ASDF_CONFIG_FILE", filepath.Join(home, "nonexistent-user-asdfrc"))
+		t.Setenv("ASDF_DATA_DIR", filepath.Join(home, ".asdf"))
+
+		assert.Nil(t, run([]string{"config", "check"}))
+	})
+
+	t.Run("config check fails on an invalid setting", func(t *testing.T) {
+		home := t.TempDir()
+		configFile := filepath.Join(home, "asdfrc")
+		assert.Nil(t, os.WriteFile(configFile, []byte("concurrency = not-a-number\n"), 0o644))
+
+		t.Setenv("HOME", home)
+		t.Setenv("ASDF_SYSTEM_CONFIG_FILE", filepath.Join(home, "nonexistent-system-asdfrc"))
+		t.Setenv("ASDF_CONFIG_FILE", configFile)
+		t.Setenv("ASDF_DATA_DIR", filepath.Join(home, ".asdf"))
+
+		assert.NotNil(t, run([]string{"config", "check"}))
+	})
+}
@@ -0,0 +1,603 @@
+// Package config reads and merges asdf's runtime configuration. Settings can
+// come from an optional system-wide file, the user's asdfrc, and an optional
+// project-level .asdfrc discovered by walking up from the working directory.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const defaultDataDir = ".asdf"
+const defaultConfigFile = ".asdfrc"
+const projectConfigFileName = ".asdfrc"
+const systemConfigFileEnvVar = "ASDF_SYSTEM_CONFIG_FILE"
+const defaultSystemConfigFile = "/etc/asdfrc"
+const strictModeEnvVar = "ASDF_CONFIG_STRICT"
+
+// Source identifies which layer of the configuration chain a value came
+// from. Layers are merged system -> user -> project, with later layers
+// overriding earlier ones.
+type Source int
+
+// The configuration layers, in increasing order of precedence.
+const (
+	SourceDefault Source = iota
+	SourceSystem
+	SourceUser
+	SourceProject
+)
+
+// String returns the human readable name of a Source, used when reporting
+// provenance to the user (e.g. via `asdf config`).
+func (s Source) String() string {
+	switch s {
+	case SourceSystem:
+		return "system"
+	case SourceUser:
+		return "user"
+	case SourceProject:
+		return "project"
+	default:
+		return "default"
+	}
+}
+
+// Config contains the locations asdf reads its configuration from. It is
+// built once at startup by LoadConfig.
+type Config struct {
+	Home       string
+	DataDir    string
+	ConfigFile string
+
+	// SystemConfigFile and ProjectConfigFile are optional. They are empty
+	// when no system-wide or project-level asdfrc was found.
+	SystemConfigFile  string
+	ProjectConfigFile string
+}
+
+// PluginRepoCheckDuration represents the parsed value of the
+// plugin_repository_last_check_duration setting.
+type PluginRepoCheckDuration struct {
+	Never bool
+	Every int
+}
+
+// Settings represents the values that can be set in an asdfrc file, along
+// with the Source that supplied each one.
+type Settings struct {
+	Loaded                            bool
+	LegacyVersionFile                 bool
+	AlwaysKeepDownload                bool
+	PluginRepositoryLastCheckDuration PluginRepoCheckDuration
+	DisablePluginShortNameRepository  bool
+	Concurrency                       string
+	Hooks                             map[string]string
+
+	// DefaultProfile is the value of the top-level default_profile key, used
+	// to pick the active profile when $ASDF_PROFILE is unset.
+	DefaultProfile string
+
+	// Profiles holds every named profile declared via a [profile "<name>"]
+	// section, keyed by name.
+	Profiles map[string]ProfileSettings
+
+	// Sources records which layer supplied each field, keyed by its asdfrc
+	// name (e.g. "legacy_version_file"). Hook sources are keyed by the hook
+	// name itself.
+	Sources map[string]Source
+
+	// Offsets records the byte offset of the line each top-level key was
+	// declared on, when the backing format makes that available (only the
+	// legacy INI-ish parser currently does). Used by Validate to point
+	// `asdf config check` at the offending line.
+	Offsets map[string]int
+}
+
+func newSettings() Settings {
+	return Settings{
+		PluginRepositoryLastCheckDuration: PluginRepoCheckDuration{Every: 60},
+		Concurrency:                       strconv.Itoa(runtime.NumCPU()),
+		Hooks:                             map[string]string{},
+		Profiles:                          map[string]ProfileSettings{},
+		Sources:                           map[string]Source{},
+		Offsets:                           map[string]int{},
+	}
+}
+
+// LoadConfig generates a Config struct populated from the environment,
+// including locating the system, user, and project asdfrc files that are
+// merged by loadSettings.
+func LoadConfig() (Config, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Config{}, err
+	}
+
+	dataDir := os.Getenv("ASDF_DATA_DIR")
+	if dataDir == "" {
+		dataDir = filepath.Join(home, defaultDataDir)
+	} else {
+		dataDir = expandTilde(dataDir, home)
+	}
+
+	configFile := os.Getenv("ASDF_CONFIG_FILE")
+	if configFile == "" {
+		configFile = filepath.Join(home, defaultConfigFile)
+	} else {
+		configFile = expandTilde(configFile, home)
+	}
+
+	systemConfigFile := os.Getenv(systemConfigFileEnvVar)
+	if systemConfigFile == "" {
+		systemConfigFile = defaultSystemConfigFile
+	}
+
+	projectConfigFile, err := findProjectConfigFile(home, configFile, systemConfigFile)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return Config{
+		Home:              home,
+		DataDir:           dataDir,
+		ConfigFile:        configFile,
+		SystemConfigFile:  systemConfigFile,
+		ProjectConfigFile: projectConfigFile,
+	}, nil
+}
+
+// findProjectConfigFile walks up from the current working directory looking
+// for a .asdfrc file, stopping once it reaches home without descending into
+// it. The project filename defaults to the same name as the user's own
+// asdfrc, so without this the walk would routinely "discover" the user's own
+// config (or a stale one left behind by ASDF_CONFIG_FILE pointing elsewhere)
+// and re-tag it as the project layer. configFile and systemConfigFile are
+// also excluded as a second line of defense for the rarer case where one of
+// them already sits somewhere on the walked path. It returns an empty
+// string, rather than an error, when none is found since a project-level
+// file is always optional.
+func findProjectConfigFile(home, configFile, systemConfigFile string) (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	home = filepath.Clean(home)
+
+	for {
+		if filepath.Clean(dir) == home {
+			return "", nil
+		}
+
+		candidate := filepath.Join(dir, projectConfigFileName)
+		if candidate != configFile && candidate != systemConfigFile {
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+func expandTilde(path, home string) string {
+	if path == "~" {
+		return home
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
+
+// loadSettings loads a single asdfrc file from disk without consulting any
+// other layer. Callers that want the full system/user/project chain should
+// use loadMergedSettings instead.
+func loadSettings(path string) (Settings, error) {
+	settings := newSettings()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return settings, err
+	}
+	defer file.Close()
+
+	parsed, err := decodeFileSettings(file, path, SourceUser)
+	if err != nil {
+		return settings, err
+	}
+
+	parsed.Loaded = true
+	return parsed, nil
+}
+
+// parseSettings reads the INI-ish asdfrc format: one `key = value` pair per
+// line. Keys that don't match a known setting are assumed to be hooks.
+// source is recorded against every field and hook populated from the file.
+func parseSettings(r io.Reader, source Source) (Settings, error) {
+	settings := newSettings()
+
+	var activeProfile string
+	inProfile := false
+	offset := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		rawLine := scanner.Text()
+		lineOffset := offset
+		offset += len(rawLine) + 1
+
+		line := strings.TrimSpace(rawLine)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if name, ok := parseProfileHeader(line); ok {
+			activeProfile = name
+			inProfile = true
+			if _, exists := settings.Profiles[name]; !exists {
+				settings.Profiles[name] = ProfileSettings{Hooks: map[string]string{}, Sources: map[string]Source{}}
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if inProfile {
+			updated, err := applyProfileSetting(settings.Profiles[activeProfile], key, value, source)
+			if err != nil {
+				return settings, err
+			}
+			settings.Profiles[activeProfile] = updated
+			continue
+		}
+
+		settings.Offsets[key] = lineOffset
+
+		switch key {
+		case "default_profile":
+			settings.DefaultProfile = value
+		case "legacy_version_file":
+			settings.LegacyVersionFile = isTruthy(value)
+		case "always_keep_download":
+			settings.AlwaysKeepDownload = isTruthy(value)
+		case "disable_plugin_short_name_repository":
+			settings.DisablePluginShortNameRepository = isTruthy(value)
+		case "concurrency":
+			settings.Concurrency = value
+		case "plugin_repository_last_check_duration":
+			duration, err := parsePluginRepoCheckDuration(value)
+			if err != nil {
+				return settings, err
+			}
+			settings.PluginRepositoryLastCheckDuration = duration
+		default:
+			settings.Hooks[key] = value
+			settings.Sources[key] = source
+			continue
+		}
+
+		settings.Sources[key] = source
+	}
+
+	if err := scanner.Err(); err != nil {
+		return settings, err
+	}
+
+	applyConcurrencyOverride(&settings, source)
+
+	return settings, nil
+}
+
+// applyConcurrencyOverride applies the ASDF_CONCURRENCY environment
+// variable and resolves the "auto" sentinel value. It is shared by every
+// format since the override is an asdf-wide behaviour, not a detail of the
+// legacy INI-ish parser.
+func applyConcurrencyOverride(settings *Settings, source Source) {
+	if concurrency := os.Getenv("ASDF_CONCURRENCY"); concurrency != "" {
+		settings.Concurrency = concurrency
+		settings.Sources["concurrency"] = source
+	}
+	if settings.Concurrency == "auto" {
+		settings.Concurrency = strconv.Itoa(runtime.NumCPU())
+	}
+}
+
+func isTruthy(value string) bool {
+	switch strings.ToLower(value) {
+	case "yes", "true", "1":
+		return true
+	default:
+		return false
+	}
+}
+
+func parsePluginRepoCheckDuration(value string) (PluginRepoCheckDuration, error) {
+	if strings.ToLower(value) == "never" {
+		return PluginRepoCheckDuration{Never: true}, nil
+	}
+
+	minutes, err := strconv.Atoi(value)
+	if err != nil {
+		return PluginRepoCheckDuration{}, fmt.Errorf("invalid plugin_repository_last_check_duration %q: %w", value, err)
+	}
+	return PluginRepoCheckDuration{Every: minutes}, nil
+}
+
+// loadLayer loads a single optional asdfrc file, tagging every value it
+// contains with source. A missing file is not an error: it simply
+// contributes nothing to the merge.
+func loadLayer(path string, source Source) (Settings, error) {
+	if path == "" {
+		return newSettings(), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newSettings(), nil
+		}
+		return newSettings(), err
+	}
+	defer file.Close()
+
+	settings, err := decodeFileSettings(file, path, source)
+	if err != nil {
+		return newSettings(), err
+	}
+	settings.Loaded = true
+	return settings, nil
+}
+
+// loadMergedSettings merges the system, user, and project asdfrc layers for
+// c, with each layer overriding the values set by the one before it. When
+// ASDF_CONFIG_STRICT=1 is set, conflicting values are reported via
+// FindConfigurationConflicts instead of being silently overridden.
+func (c Config) loadMergedSettings() (Settings, error) {
+	system, err := loadLayer(c.SystemConfigFile, SourceSystem)
+	if err != nil {
+		return Settings{}, err
+	}
+	user, err := loadLayer(c.ConfigFile, SourceUser)
+	if err != nil {
+		return Settings{}, err
+	}
+	project, err := loadLayer(c.ProjectConfigFile, SourceProject)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	if os.Getenv(strictModeEnvVar) == "1" {
+		if conflicts := FindConfigurationConflicts(system, user, project); conflicts != nil {
+			return Settings{}, conflicts
+		}
+	}
+
+	merged := mergeSettings(system, user, project)
+	merged.Loaded = system.Loaded || user.Loaded || project.Loaded
+
+	// ASDF_CONCURRENCY must win even when none of the three layers exist on
+	// disk, since applyConcurrencyOverride otherwise only runs as part of
+	// parsing/decoding an actual file.
+	applyConcurrencyOverride(&merged, SourceDefault)
+
+	return merged, nil
+}
+
+// mergeSettings combines layers in increasing order of precedence, returning
+// a single Settings struct. Later, non-zero-source layers win field by
+// field.
+func mergeSettings(layers ...Settings) Settings {
+	merged := newSettings()
+
+	for _, layer := range layers {
+		if layer.DefaultProfile != "" {
+			merged.DefaultProfile = layer.DefaultProfile
+		}
+		mergeProfiles(merged.Profiles, layer.Profiles)
+
+		for key, source := range layer.Sources {
+			if _, isHook := layer.Hooks[key]; isHook {
+				merged.Hooks[key] = layer.Hooks[key]
+				merged.Sources[key] = source
+				continue
+			}
+
+			switch key {
+			case "legacy_version_file":
+				merged.LegacyVersionFile = layer.LegacyVersionFile
+			case "always_keep_download":
+				merged.AlwaysKeepDownload = layer.AlwaysKeepDownload
+			case "disable_plugin_short_name_repository":
+				merged.DisablePluginShortNameRepository = layer.DisablePluginShortNameRepository
+			case "concurrency":
+				merged.Concurrency = layer.Concurrency
+			case "plugin_repository_last_check_duration":
+				merged.PluginRepositoryLastCheckDuration = layer.PluginRepositoryLastCheckDuration
+			}
+			merged.Sources[key] = source
+		}
+	}
+
+	return merged
+}
+
+// ConfigConflict describes a single hook whose command disagrees between two
+// configuration layers.
+type ConfigConflict struct {
+	Key          string
+	SystemValue  string
+	UserValue    string
+	ProjectValue string
+}
+
+// ConfigConflictError is returned by FindConfigurationConflicts (via
+// loadMergedSettings) when ASDF_CONFIG_STRICT=1 and two or more layers
+// disagree on a hook's command. It is modeled on the daemon config conflict
+// reporting Docker uses to validate merged configuration files.
+type ConfigConflictError struct {
+	Conflicts []ConfigConflict
+}
+
+func (e *ConfigConflictError) Error() string {
+	keys := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		keys[i] = c.Key
+	}
+	return fmt.Sprintf("conflicting asdfrc hooks across system/user/project config: %s", strings.Join(keys, ", "))
+}
+
+// FindConfigurationConflicts compares the hooks parsed from the system,
+// user, and project asdfrc layers and returns one ConfigConflictError
+// (wrapped as a slice-friendly error) describing every hook set by more than
+// one layer with a disagreeing command. It returns nil when there are no
+// conflicts.
+//
+// Only hooks are considered here, not the scalar settings (concurrency,
+// legacy_version_file, and so on). A project or user layer overriding a
+// scalar setting is the entire point of the layered precedence chain, not a
+// mistake worth failing a run over. A hook is different: it's a shell
+// command that runs silently, so a project directory quietly swapping in a
+// different command than the one the user configured is exactly the kind of
+// surprise strict mode exists to catch.
+func FindConfigurationConflicts(system, user, project Settings) *ConfigConflictError {
+	layers := map[Source]Settings{SourceSystem: system, SourceUser: user, SourceProject: project}
+
+	keys := map[string]bool{}
+	for _, layer := range layers {
+		for key := range layer.Hooks {
+			keys[key] = true
+		}
+	}
+
+	var conflicts []ConfigConflict
+	for key := range keys {
+		values := map[Source]string{}
+		for source, layer := range layers {
+			if _, ok := layer.Hooks[key]; !ok {
+				continue
+			}
+			values[source] = layer.Hooks[key]
+		}
+
+		if len(values) < 2 {
+			continue
+		}
+
+		var distinct = map[string]bool{}
+		for _, v := range values {
+			distinct[v] = true
+		}
+		if len(distinct) < 2 {
+			continue
+		}
+
+		conflicts = append(conflicts, ConfigConflict{
+			Key:          key,
+			SystemValue:  values[SourceSystem],
+			UserValue:    values[SourceUser],
+			ProjectValue: values[SourceProject],
+		})
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return &ConfigConflictError{Conflicts: conflicts}
+}
+
+// LegacyVersionFile returns the legacy_version_file setting, resolved
+// through the active profile.
+func (c Config) LegacyVersionFile() (bool, error) {
+	settings, err := c.resolvedSettings()
+	if err != nil {
+		return false, err
+	}
+	return settings.LegacyVersionFile, nil
+}
+
+// AlwaysKeepDownload returns the always_keep_download setting, resolved
+// through the active profile.
+func (c Config) AlwaysKeepDownload() (bool, error) {
+	settings, err := c.resolvedSettings()
+	if err != nil {
+		return false, err
+	}
+	return settings.AlwaysKeepDownload, nil
+}
+
+// PluginRepositoryLastCheckDuration returns the
+// plugin_repository_last_check_duration setting, resolved through the
+// active profile.
+func (c Config) PluginRepositoryLastCheckDuration() (PluginRepoCheckDuration, error) {
+	settings, err := c.resolvedSettings()
+	if err != nil {
+		return PluginRepoCheckDuration{Every: 60}, err
+	}
+	return settings.PluginRepositoryLastCheckDuration, nil
+}
+
+// DisablePluginShortNameRepository returns the
+// disable_plugin_short_name_repository setting, resolved through the
+// active profile.
+func (c Config) DisablePluginShortNameRepository() (bool, error) {
+	settings, err := c.resolvedSettings()
+	if err != nil {
+		return false, err
+	}
+	return settings.DisablePluginShortNameRepository, nil
+}
+
+// Concurrency returns the concurrency setting, resolved through the active
+// profile.
+func (c Config) Concurrency() (string, error) {
+	settings, err := c.resolvedSettings()
+	if err != nil {
+		return "", err
+	}
+	return settings.Concurrency, nil
+}
+
+// GetHook returns the command configured for the named hook (e.g.
+// "pre_asdf_plugin_add"), merged across the system, user, and project
+// asdfrc layers. It returns an empty string when the hook is not set
+// anywhere.
+func (c Config) GetHook(name string) (string, error) {
+	hookCmd, _, err := c.GetHookWithSource(name)
+	return hookCmd, err
+}
+
+// GetHookWithSource is like GetHook but also reports which layer the
+// winning value came from, for use by diagnostic commands like
+// `asdf config`. When the active profile overrides the hook, the source
+// reported is that of the layer the base (unresolved) value came from.
+func (c Config) GetHookWithSource(name string) (string, Source, error) {
+	merged, err := c.loadMergedSettings()
+	if err != nil {
+		return "", SourceDefault, err
+	}
+
+	resolved := applyProfile(merged, profileName(merged))
+
+	source, ok := merged.Sources[name]
+	if !ok {
+		return resolved.Hooks[name], SourceDefault, nil
+	}
+	return resolved.Hooks[name], source, nil
+}
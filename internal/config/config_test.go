@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -37,6 +38,58 @@ func TestLoadConfig(t *testing.T) {
 	})
 }
 
+// chdir changes the working directory for the duration of the test and
+// restores it on cleanup.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	original, err := os.Getwd()
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chdir(dir))
+	t.Cleanup(func() { assert.Nil(t, os.Chdir(original)) })
+}
+
+func TestFindProjectConfigFile(t *testing.T) {
+	t.Run("Stops at home instead of re-discovering the user's own asdfrc", func(t *testing.T) {
+		home := t.TempDir()
+		userConfigFile := filepath.Join(home, defaultConfigFile)
+		assert.Nil(t, os.WriteFile(userConfigFile, []byte(""), 0o644))
+
+		workDir := filepath.Join(home, "work", "proj")
+		assert.Nil(t, os.MkdirAll(workDir, 0o755))
+		chdir(t, workDir)
+
+		found, err := findProjectConfigFile(home, userConfigFile, "/etc/asdfrc")
+		assert.Nil(t, err)
+		assert.Equal(t, "", found, "Expected the walk to stop at home rather than return the user's own asdfrc")
+	})
+
+	t.Run("Finds a project file between the working directory and home", func(t *testing.T) {
+		home := t.TempDir()
+		workDir := filepath.Join(home, "work", "proj")
+		assert.Nil(t, os.MkdirAll(workDir, 0o755))
+		projectConfigFile := filepath.Join(home, "work", projectConfigFileName)
+		assert.Nil(t, os.WriteFile(projectConfigFile, []byte(""), 0o644))
+		chdir(t, workDir)
+
+		found, err := findProjectConfigFile(home, filepath.Join(home, defaultConfigFile), "/etc/asdfrc")
+		assert.Nil(t, err)
+		assert.Equal(t, projectConfigFile, found)
+	})
+
+	t.Run("Skips a candidate that is actually the resolved system config file", func(t *testing.T) {
+		home := t.TempDir()
+		workDir := filepath.Join(home, "work")
+		assert.Nil(t, os.MkdirAll(workDir, 0o755))
+		systemConfigFile := filepath.Join(home, projectConfigFileName)
+		assert.Nil(t, os.WriteFile(systemConfigFile, []byte(""), 0o644))
+		chdir(t, workDir)
+
+		found, err := findProjectConfigFile(filepath.Join(home, "nonexistent-home"), filepath.Join(home, defaultConfigFile), systemConfigFile)
+		assert.Nil(t, err)
+		assert.Equal(t, "", found, "Expected the candidate matching SystemConfigFile to be skipped")
+	})
+}
+
 func TestLoadSettings(t *testing.T) {
 	t.Run("When given invalid path returns error", func(t *testing.T) {
 		settings, err := loadSettings("./foobar")
@@ -193,3 +246,336 @@ func TestConfigGetHook(t *testing.T) {
 		assert.Empty(t, hookCmd)
 	})
 }
+
+func TestDecoderRoundTrip(t *testing.T) {
+	t.Run("INI, TOML, and JSON backends produce identical Settings for equivalent input", func(t *testing.T) {
+		ini, err := loadLayer("testdata/asdfrc", SourceUser)
+		assert.Nil(t, err)
+
+		tomlSettings, err := loadLayer("testdata/asdfrc.toml", SourceUser)
+		assert.Nil(t, err)
+
+		jsonSettings, err := loadLayer("testdata/asdfrc.json", SourceUser)
+		assert.Nil(t, err)
+
+		// Offsets is populated only by the legacy parser, which can report a
+		// byte position; TOML/JSON decoding doesn't track one, so it's
+		// excluded from this comparison.
+		ini.Offsets = nil
+		tomlSettings.Offsets = nil
+		jsonSettings.Offsets = nil
+
+		assert.Equal(t, ini, tomlSettings, "TOML backend should produce the same Settings as the legacy format")
+		assert.Equal(t, ini, jsonSettings, "JSON backend should produce the same Settings as the legacy format")
+	})
+}
+
+func TestDecoderRoundTripProfiles(t *testing.T) {
+	t.Run("TOML and JSON backends decode profile tables", func(t *testing.T) {
+		toml, err := loadLayer("testdata/profiles-asdfrc.toml", SourceUser)
+		assert.Nil(t, err)
+
+		json, err := loadLayer("testdata/profiles-asdfrc.json", SourceUser)
+		assert.Nil(t, err)
+
+		for _, settings := range []Settings{toml, json} {
+			assert.Equal(t, "work", settings.DefaultProfile)
+			assert.Equal(t, "2", *settings.Profiles["work"].Concurrency)
+			assert.Equal(t, "echo work hook", settings.Profiles["work"].Hooks["pre_asdf_plugin_add"])
+			assert.True(t, *settings.Profiles["personal"].LegacyVersionFile)
+		}
+	})
+}
+
+func TestConfigProfiles(t *testing.T) {
+	config := Config{ConfigFile: "testdata/profiles-asdfrc"}
+
+	t.Run("Falls back to default_profile when ASDF_PROFILE is unset", func(t *testing.T) {
+		assert.Equal(t, "work", config.Profile())
+	})
+
+	t.Run("ASDF_PROFILE takes precedence over default_profile", func(t *testing.T) {
+		t.Setenv("ASDF_PROFILE", "personal")
+		assert.Equal(t, "personal", config.Profile())
+	})
+
+	t.Run("AvailableProfiles lists every declared profile", func(t *testing.T) {
+		assert.Equal(t, []string{"personal", "work"}, config.AvailableProfiles())
+	})
+
+	t.Run("Active profile overrides base settings", func(t *testing.T) {
+		concurrency, err := config.Concurrency()
+		assert.Nil(t, err)
+		assert.Equal(t, "2", concurrency, "Expected the work profile's concurrency to win")
+
+		hookCmd, err := config.GetHook("pre_asdf_plugin_add")
+		assert.Nil(t, err)
+		assert.Equal(t, "echo work hook", hookCmd)
+	})
+
+	t.Run("ASDF_CONCURRENCY still wins over a profile's concurrency value", func(t *testing.T) {
+		t.Setenv("ASDF_CONCURRENCY", "99")
+
+		concurrency, err := config.Concurrency()
+		assert.Nil(t, err)
+		assert.Equal(t, "99", concurrency, "Expected the env override to win over both the base and the active profile")
+	})
+
+	t.Run("A profile's concurrency = auto resolves to a CPU count", func(t *testing.T) {
+		autoProfileConfig := Config{ConfigFile: "testdata/profiles-auto-asdfrc"}
+
+		concurrency, err := autoProfileConfig.Concurrency()
+		assert.Nil(t, err)
+		assert.Equal(t, strconv.Itoa(runtime.NumCPU()), concurrency, "Expected the profile's \"auto\" to resolve just like the base setting does")
+	})
+
+	t.Run("Switching profiles changes which overrides apply", func(t *testing.T) {
+		t.Setenv("ASDF_PROFILE", "personal")
+
+		legacyFile, err := config.LegacyVersionFile()
+		assert.Nil(t, err)
+		assert.True(t, legacyFile, "Expected the personal profile to enable legacy_version_file")
+
+		concurrency, err := config.Concurrency()
+		assert.Nil(t, err)
+		assert.Equal(t, "5", concurrency, "personal profile doesn't override concurrency, so the base value should win")
+	})
+
+	t.Run("Unknown profile falls through to base settings", func(t *testing.T) {
+		t.Setenv("ASDF_PROFILE", "nonexistent")
+
+		concurrency, err := config.Concurrency()
+		assert.Nil(t, err)
+		assert.Equal(t, "5", concurrency)
+	})
+}
+
+func TestConfigLayering(t *testing.T) {
+	config := Config{
+		SystemConfigFile:  "testdata/system-asdfrc",
+		ConfigFile:        "testdata/asdfrc",
+		ProjectConfigFile: "testdata/project-asdfrc",
+	}
+
+	t.Run("Project settings override user settings which override system settings", func(t *testing.T) {
+		legacyFile, err := config.LegacyVersionFile()
+		assert.Nil(t, err)
+		assert.True(t, legacyFile, "Expected project value to win over user and system values")
+
+		concurrency, err := config.Concurrency()
+		assert.Nil(t, err)
+		assert.Equal(t, "5", concurrency, "Expected user value to win since project doesn't set concurrency")
+	})
+
+	t.Run("Hooks are merged with project taking precedence", func(t *testing.T) {
+		hookCmd, source, err := config.GetHookWithSource("pre_asdf_plugin_add")
+		assert.Nil(t, err)
+		assert.Equal(t, "echo project hook", hookCmd)
+		assert.Equal(t, SourceProject, source)
+	})
+}
+
+func TestFindConfigurationConflicts(t *testing.T) {
+	t.Run("Reports no conflicts when layers agree or don't overlap", func(t *testing.T) {
+		system, err := loadLayer("testdata/system-asdfrc", SourceSystem)
+		assert.Nil(t, err)
+		user, err := loadLayer("testdata/empty-asdfrc", SourceUser)
+		assert.Nil(t, err)
+		project, err := loadLayer("testdata/empty-asdfrc", SourceProject)
+		assert.Nil(t, err)
+
+		assert.Nil(t, FindConfigurationConflicts(system, user, project))
+	})
+
+	t.Run("Reports a conflict when two layers disagree on a hook command", func(t *testing.T) {
+		system, err := loadLayer("testdata/system-asdfrc", SourceSystem)
+		assert.Nil(t, err)
+		user, err := loadLayer("testdata/asdfrc", SourceUser)
+		assert.Nil(t, err)
+		project, err := loadLayer("testdata/empty-asdfrc", SourceProject)
+		assert.Nil(t, err)
+
+		conflicts := FindConfigurationConflicts(system, user, project)
+		assert.NotNil(t, conflicts)
+
+		keys := make([]string, len(conflicts.Conflicts))
+		for i, c := range conflicts.Conflicts {
+			keys[i] = c.Key
+		}
+		assert.Contains(t, keys, "pre_asdf_plugin_add")
+	})
+
+	t.Run("Does not report a conflict when layers merely override a scalar setting", func(t *testing.T) {
+		// system and testdata/asdfrc disagree on concurrency (1 vs 5), but
+		// that's the layered precedence chain working as intended, not a
+		// conflict worth failing strict mode over.
+		system, err := loadLayer("testdata/system-asdfrc", SourceSystem)
+		assert.Nil(t, err)
+		user, err := loadLayer("testdata/asdfrc", SourceUser)
+		assert.Nil(t, err)
+
+		conflicts := FindConfigurationConflicts(system, user, newSettings())
+		assert.NotNil(t, conflicts)
+
+		for _, c := range conflicts.Conflicts {
+			assert.NotEqual(t, "concurrency", c.Key, "Overriding a scalar setting should not be reported as a conflict")
+		}
+	})
+
+	t.Run("Strict mode surfaces hook conflicts as an error from the config accessors", func(t *testing.T) {
+		t.Setenv("ASDF_CONFIG_STRICT", "1")
+
+		config := Config{
+			SystemConfigFile: "testdata/system-asdfrc",
+			ConfigFile:       "testdata/asdfrc",
+		}
+
+		_, err := config.Concurrency()
+		assert.NotNil(t, err, "Expected conflicting concurrency values to be reported in strict mode")
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	t.Run("Reports no diagnostics for a valid, writable config", func(t *testing.T) {
+		config := Config{ConfigFile: "testdata/asdfrc", DataDir: t.TempDir()}
+		assert.Empty(t, config.Validate())
+	})
+
+	t.Run("Reports an invalid concurrency value", func(t *testing.T) {
+		config := Config{ConfigFile: "testdata/invalid-asdfrc", DataDir: t.TempDir()}
+
+		diagnostics := config.Validate()
+
+		var found bool
+		for _, d := range diagnostics {
+			if d.Field == "concurrency" {
+				found = true
+				assert.Equal(t, SeverityError, d.Severity)
+				assert.Equal(t, SourceUser, d.Source)
+			}
+		}
+		assert.True(t, found, "Expected a diagnostic for the invalid concurrency value")
+	})
+
+	t.Run("Reports an invalid plugin_repository_last_check_duration value", func(t *testing.T) {
+		config := Config{ConfigFile: "testdata/invalid-asdfrc", DataDir: t.TempDir()}
+
+		diagnostics := config.Validate()
+
+		var found bool
+		for _, d := range diagnostics {
+			if d.Field == "plugin_repository_last_check_duration" {
+				found = true
+				assert.Equal(t, SeverityError, d.Severity)
+			}
+		}
+		assert.True(t, found, "Expected a diagnostic for the invalid duration value")
+	})
+
+	t.Run("Suggests the nearest known key for an unknown key", func(t *testing.T) {
+		config := Config{ConfigFile: "testdata/invalid-asdfrc", DataDir: t.TempDir()}
+
+		diagnostics := config.Validate()
+
+		var found bool
+		for _, d := range diagnostics {
+			if d.Field == "cocnurrency" {
+				found = true
+				assert.Equal(t, SeverityWarning, d.Severity)
+				assert.Contains(t, d.Message, "concurrency")
+			}
+		}
+		assert.True(t, found, "Expected a diagnostic suggesting 'concurrency' for the 'cocnurrency' typo")
+	})
+
+	t.Run("Does not flag a well-formed hook name", func(t *testing.T) {
+		config := Config{ConfigFile: "testdata/invalid-asdfrc", DataDir: t.TempDir()}
+
+		diagnostics := config.Validate()
+
+		for _, d := range diagnostics {
+			assert.NotEqual(t, "pre_asdf_plugin_add", d.Field)
+		}
+	})
+
+	t.Run("Reports an invalid concurrency value set by the active profile", func(t *testing.T) {
+		config := Config{ConfigFile: "testdata/invalid-profile-asdfrc", DataDir: t.TempDir()}
+
+		diagnostics := config.Validate()
+
+		var found bool
+		for _, d := range diagnostics {
+			if d.Field == "concurrency" {
+				found = true
+				assert.Equal(t, SeverityError, d.Severity)
+				assert.Contains(t, d.Message, "not-a-number")
+				assert.Equal(t, SourceUser, d.Source, "Expected the diagnostic to point at the file that declared the profile, not the system asdfrc that set the base concurrency value")
+			}
+		}
+		assert.True(t, found, "Expected a diagnostic for the work profile's invalid concurrency value")
+	})
+
+	t.Run("Reports an invalid concurrency value set by a declared but inactive profile", func(t *testing.T) {
+		config := Config{ConfigFile: "testdata/inactive-profile-asdfrc", DataDir: t.TempDir()}
+
+		diagnostics := config.Validate()
+
+		var found bool
+		for _, d := range diagnostics {
+			if d.Field == "concurrency" && strings.Contains(d.Message, "home") {
+				found = true
+				assert.Equal(t, SeverityError, d.Severity)
+				assert.Contains(t, d.Message, "not-a-number")
+			}
+		}
+		assert.True(t, found, "Expected a diagnostic for the inactive home profile's invalid concurrency value even though work is active")
+	})
+
+	t.Run("Reports an unknown key declared inside a declared but inactive profile", func(t *testing.T) {
+		config := Config{ConfigFile: "testdata/inactive-profile-asdfrc", DataDir: t.TempDir()}
+
+		diagnostics := config.Validate()
+
+		var found bool
+		for _, d := range diagnostics {
+			if d.Field == "cocnurrency" && strings.Contains(d.Message, "home") {
+				found = true
+				assert.Equal(t, SeverityWarning, d.Severity)
+			}
+		}
+		assert.True(t, found, "Expected a diagnostic for the inactive home profile's 'cocnurrency' typo")
+	})
+
+	t.Run("Reports an unknown key declared inside the active profile", func(t *testing.T) {
+		config := Config{ConfigFile: "testdata/invalid-profile-asdfrc", DataDir: t.TempDir()}
+
+		diagnostics := config.Validate()
+
+		var found bool
+		for _, d := range diagnostics {
+			if d.Field == "cocnurrency" {
+				found = true
+				assert.Equal(t, SeverityWarning, d.Severity)
+				assert.Equal(t, SourceUser, d.Source, "Expected the diagnostic to point at the file that declared the profile")
+				assert.Contains(t, d.Message, "work")
+				assert.Contains(t, d.Message, "concurrency")
+			}
+		}
+		assert.True(t, found, "Expected a diagnostic for the work profile's 'cocnurrency' typo")
+	})
+
+	t.Run("Reports an unwritable DataDir", func(t *testing.T) {
+		config := Config{ConfigFile: "testdata/asdfrc", DataDir: "/nonexistent-root/asdf-data"}
+
+		diagnostics := config.Validate()
+
+		var found bool
+		for _, d := range diagnostics {
+			if d.Field == "data_dir" {
+				found = true
+				assert.Equal(t, SeverityError, d.Severity)
+			}
+		}
+		assert.True(t, found, "Expected a diagnostic for the unwritable data dir")
+	})
+}
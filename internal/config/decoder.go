@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// SettingsDecoder decodes an asdfrc file of some on-disk format into a
+// Settings struct. The legacy `key = value` format is built in and handled
+// directly by parseSettings; SettingsDecoder exists for additional formats
+// such as TOML and JSON, and for third parties embedding asdf as a library
+// who want to support their own.
+type SettingsDecoder interface {
+	Decode(r io.Reader, out *Settings) error
+}
+
+// decoders maps a file extension (as returned by filepath.Ext) to the
+// SettingsDecoder responsible for it. Extensions with no registered decoder
+// fall back to the legacy format.
+var decoders = map[string]SettingsDecoder{}
+
+// RegisterDecoder makes a SettingsDecoder available for asdfrc files whose
+// name ends in ext (e.g. ".toml"). It is typically called from an init
+// function, and panics on a duplicate registration since that almost always
+// indicates two packages fighting over the same format.
+func RegisterDecoder(ext string, decoder SettingsDecoder) {
+	if _, exists := decoders[ext]; exists {
+		panic(fmt.Sprintf("config: decoder already registered for %q", ext))
+	}
+	decoders[ext] = decoder
+}
+
+// GetDecoder returns the SettingsDecoder registered for ext, if any.
+func GetDecoder(ext string) (SettingsDecoder, bool) {
+	decoder, ok := decoders[ext]
+	return decoder, ok
+}
+
+// decodeRawDuration converts the loosely-typed value produced by the TOML
+// and JSON decoders for plugin_repository_last_check_duration into a
+// PluginRepoCheckDuration. The legacy format only ever sees a string, but
+// TOML and JSON both allow the minutes value to be written as a bare
+// number.
+func decodeRawDuration(value interface{}) (PluginRepoCheckDuration, error) {
+	switch v := value.(type) {
+	case nil:
+		return PluginRepoCheckDuration{Every: 60}, nil
+	case string:
+		return parsePluginRepoCheckDuration(v)
+	case int:
+		return PluginRepoCheckDuration{Every: v}, nil
+	case int64:
+		return PluginRepoCheckDuration{Every: int(v)}, nil
+	case float64:
+		return PluginRepoCheckDuration{Every: int(v)}, nil
+	default:
+		return PluginRepoCheckDuration{}, fmt.Errorf("invalid plugin_repository_last_check_duration value: %v", value)
+	}
+}
+
+// buildProfileSettings assembles a ProfileSettings from the loosely-typed
+// fields a structured (TOML/JSON) profile table decodes to. It's shared by
+// every structured decoder so each one only has to supply its own raw,
+// tagged struct.
+func buildProfileSettings(legacyVersionFile, alwaysKeepDownload, disablePluginShortNameRepository *bool, concurrency *string, duration interface{}, hooks map[string]string) (ProfileSettings, error) {
+	profile := ProfileSettings{
+		LegacyVersionFile:                legacyVersionFile,
+		AlwaysKeepDownload:               alwaysKeepDownload,
+		DisablePluginShortNameRepository: disablePluginShortNameRepository,
+		Concurrency:                      concurrency,
+		Hooks:                            hooks,
+		Sources:                          map[string]Source{},
+	}
+
+	// Rewritten to the real layer source by decodeFileSettings once decoding
+	// finishes, the same way Settings.Sources is, for every field the caller
+	// actually set.
+	if legacyVersionFile != nil {
+		profile.Sources["legacy_version_file"] = SourceDefault
+	}
+	if alwaysKeepDownload != nil {
+		profile.Sources["always_keep_download"] = SourceDefault
+	}
+	if disablePluginShortNameRepository != nil {
+		profile.Sources["disable_plugin_short_name_repository"] = SourceDefault
+	}
+	if concurrency != nil {
+		profile.Sources["concurrency"] = SourceDefault
+	}
+	for name := range hooks {
+		profile.Sources[name] = SourceDefault
+	}
+
+	if duration != nil {
+		parsed, err := decodeRawDuration(duration)
+		if err != nil {
+			return profile, err
+		}
+		profile.PluginRepositoryLastCheckDuration = &parsed
+		profile.Sources["plugin_repository_last_check_duration"] = SourceDefault
+	}
+
+	return profile, nil
+}
+
+// decodeFileSettings reads path with whichever decoder its extension
+// dispatches to, falling back to the legacy INI-ish format when no decoder
+// is registered for it. Registered decoders report which fields were
+// present by populating Settings.Sources with a placeholder value; we
+// rewrite those placeholders to the real source here since decoders have
+// no notion of system/user/project layering.
+func decodeFileSettings(r io.Reader, path string, source Source) (Settings, error) {
+	decoder, ok := GetDecoder(filepath.Ext(path))
+	if !ok {
+		return parseSettings(r, source)
+	}
+
+	settings := newSettings()
+	if err := decoder.Decode(r, &settings); err != nil {
+		return settings, err
+	}
+
+	for key := range settings.Sources {
+		settings.Sources[key] = source
+	}
+	for name, profile := range settings.Profiles {
+		for key := range profile.Sources {
+			profile.Sources[key] = source
+		}
+		settings.Profiles[name] = profile
+	}
+	applyConcurrencyOverride(&settings, source)
+
+	return settings, nil
+}
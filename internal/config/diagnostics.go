@@ -0,0 +1,351 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Severity indicates how serious a Diagnostic is. Warnings describe
+// problems asdf can recover from by falling back to a default; errors
+// describe values that can't be resolved to anything sensible.
+type Severity int
+
+// The severities a Diagnostic can carry.
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+// String returns the human readable name of a Severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	default:
+		return "warning"
+	}
+}
+
+// Diagnostic describes a single problem found by Config.Validate.
+type Diagnostic struct {
+	Severity Severity
+	Field    string
+	Source   Source
+	Message  string
+
+	// Offset is the byte offset of the offending line within its asdfrc
+	// file, or nil when the backing format doesn't expose one (the TOML
+	// and JSON backends currently don't).
+	Offset *int
+}
+
+// knownSettingKeys lists every recognized top-level asdfrc key. Unknown
+// keys are suggested against this list via Levenshtein distance.
+var knownSettingKeys = []string{
+	"legacy_version_file",
+	"always_keep_download",
+	"disable_plugin_short_name_repository",
+	"concurrency",
+	"plugin_repository_last_check_duration",
+	"default_profile",
+}
+
+var hookNameRegexp = regexp.MustCompile(`^(pre|post)_asdf_[a-z0-9_]+$`)
+
+// Validate checks c's configuration for problems the lenient asdfrc parser
+// doesn't itself reject: unknown keys, hook names that don't match the
+// pre_asdf_*/post_asdf_* grammar, an invalid concurrency or
+// plugin_repository_last_check_duration value, and an unwritable DataDir.
+// Concurrency, plugin_repository_last_check_duration, and hook names are
+// checked against every declared profile's resolved values, not just the
+// active profile's, since switching $ASDF_PROFILE to a profile that was
+// never checked shouldn't be how its asdfrc mistakes are discovered.
+// It reports every problem as a Diagnostic rather than stopping at the
+// first, so `asdf config check` can show the user everything at once.
+func (c Config) Validate() []Diagnostic {
+	var diagnostics []Diagnostic
+
+	layers := []struct {
+		path   string
+		source Source
+	}{
+		{c.SystemConfigFile, SourceSystem},
+		{c.ConfigFile, SourceUser},
+		{c.ProjectConfigFile, SourceProject},
+	}
+
+	for _, layer := range layers {
+		settings, err := loadLayer(layer.path, layer.source)
+		if err != nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Field:    layer.path,
+				Source:   layer.source,
+				Message:  fmt.Sprintf("failed to read asdfrc: %s", err),
+			})
+			continue
+		}
+		diagnostics = append(diagnostics, validateHookNames(settings, layer.source)...)
+	}
+
+	merged, err := c.loadMergedSettings()
+	if err != nil {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Field:    "asdfrc",
+			Message:  err.Error(),
+		})
+		return diagnostics
+	}
+
+	activeProfile := profileName(merged)
+	diagnostics = append(diagnostics, validateProfileSettings(merged, activeProfile, false)...)
+
+	profileNames := make([]string, 0, len(merged.Profiles))
+	for name := range merged.Profiles {
+		profileNames = append(profileNames, name)
+	}
+	sort.Strings(profileNames)
+
+	for _, name := range profileNames {
+		diagnostics = append(diagnostics, validateProfileHookNames(merged, name)...)
+		if name == activeProfile {
+			continue
+		}
+		diagnostics = append(diagnostics, validateProfileSettings(merged, name, true)...)
+	}
+
+	diagnostics = append(diagnostics, c.validateDataDir()...)
+
+	return diagnostics
+}
+
+// validateProfileSettings checks the concurrency and
+// plugin_repository_last_check_duration values that would be in effect if
+// name were the active profile. label prefixes each diagnostic's message
+// with the profile's name: the currently active profile's diagnostics read
+// fine without it (they describe what Config's accessors hand back right
+// now), but a diagnostic about some other, currently-inactive profile needs
+// the name or there'd be no way to tell which asdfrc section it's about.
+func validateProfileSettings(merged Settings, name string, label bool) []Diagnostic {
+	resolved := applyProfile(merged, name)
+
+	diagnostics := append(validateConcurrency(resolved), validatePluginRepoCheckDuration(resolved)...)
+	if label {
+		for i := range diagnostics {
+			diagnostics[i].Message = fmt.Sprintf("profile %q: %s", name, diagnostics[i].Message)
+		}
+	}
+	return diagnostics
+}
+
+// validateProfileHookNames reports invalid hook names declared inside the
+// named profile's section. The per-layer validateHookNames above can't see
+// these: profile-scoped keys are parsed into Settings.Profiles, not
+// Settings.Hooks, so a typoed hook name there would otherwise reach GetHook
+// unvalidated.
+func validateProfileHookNames(merged Settings, name string) []Diagnostic {
+	profile, ok := merged.Profiles[name]
+	if !ok || len(profile.Hooks) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(profile.Hooks))
+	for key := range profile.Hooks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var diagnostics []Diagnostic
+	for _, key := range keys {
+		if hookNameRegexp.MatchString(key) {
+			continue
+		}
+
+		message := fmt.Sprintf("unknown key %q in profile %q", key, name)
+		if suggestion := nearestKey(key, knownSettingKeys); suggestion != "" {
+			message = fmt.Sprintf("%s (did you mean %q?)", message, suggestion)
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityWarning,
+			Field:    key,
+			Source:   profile.Sources[key],
+			Message:  message,
+		})
+	}
+
+	return diagnostics
+}
+
+// validateHookNames reports every key in settings.Hooks that doesn't match
+// the pre_asdf_*/post_asdf_* grammar. Since the parser stores any
+// unrecognized top-level key as a hook, this also catches plain typos of a
+// known setting name.
+func validateHookNames(settings Settings, source Source) []Diagnostic {
+	keys := make([]string, 0, len(settings.Hooks))
+	for key := range settings.Hooks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var diagnostics []Diagnostic
+	for _, key := range keys {
+		if hookNameRegexp.MatchString(key) {
+			continue
+		}
+
+		message := fmt.Sprintf("unknown key %q", key)
+		if suggestion := nearestKey(key, knownSettingKeys); suggestion != "" {
+			message = fmt.Sprintf("%s (did you mean %q?)", message, suggestion)
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityWarning,
+			Field:    key,
+			Source:   source,
+			Message:  message,
+			Offset:   offsetFor(settings, key),
+		})
+	}
+
+	return diagnostics
+}
+
+func offsetFor(settings Settings, key string) *int {
+	offset, ok := settings.Offsets[key]
+	if !ok {
+		return nil
+	}
+	return &offset
+}
+
+// validateConcurrency checks that the merged concurrency setting is either
+// "auto" or a positive integer. In practice "auto" is already resolved to
+// a CPU count by the time Settings reaches here, but the check is written
+// against the raw value so it still holds if that resolution ever moves.
+func validateConcurrency(settings Settings) []Diagnostic {
+	if settings.Concurrency == "auto" {
+		return nil
+	}
+
+	value, err := strconv.Atoi(settings.Concurrency)
+	if err != nil || value <= 0 {
+		return []Diagnostic{{
+			Severity: SeverityError,
+			Field:    "concurrency",
+			Source:   settings.Sources["concurrency"],
+			Message:  fmt.Sprintf("concurrency must be a positive integer or %q, got %q", "auto", settings.Concurrency),
+		}}
+	}
+	return nil
+}
+
+func validatePluginRepoCheckDuration(settings Settings) []Diagnostic {
+	duration := settings.PluginRepositoryLastCheckDuration
+	if duration.Never || duration.Every > 0 {
+		return nil
+	}
+
+	return []Diagnostic{{
+		Severity: SeverityError,
+		Field:    "plugin_repository_last_check_duration",
+		Source:   settings.Sources["plugin_repository_last_check_duration"],
+		Message:  fmt.Sprintf("plugin_repository_last_check_duration must be %q or a positive number of minutes, got %d", "never", duration.Every),
+	}}
+}
+
+// validateDataDir checks that c.DataDir (or its nearest existing parent)
+// can actually be written to, so a permissions problem surfaces as a
+// diagnostic instead of a confusing failure the first time asdf tries to
+// install something.
+func (c Config) validateDataDir() []Diagnostic {
+	if c.DataDir == "" {
+		return nil
+	}
+
+	dir := c.DataDir
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		dir = filepath.Dir(dir)
+	}
+
+	probe, err := os.CreateTemp(dir, ".asdf-config-check-*")
+	if err != nil {
+		return []Diagnostic{{
+			Severity: SeverityError,
+			Field:    "data_dir",
+			Message:  fmt.Sprintf("%s is not writable: %s", c.DataDir, err),
+		}}
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// nearestKey returns the candidate closest to key by Levenshtein distance,
+// or "" when nothing is close enough to be a plausible typo.
+func nearestKey(key string, candidates []string) string {
+	best := ""
+	bestDistance := -1
+
+	for _, candidate := range candidates {
+		distance := levenshteinDistance(key, candidate)
+		if bestDistance == -1 || distance < bestDistance {
+			bestDistance = distance
+			best = candidate
+		}
+	}
+
+	if bestDistance > len(key)/2+1 {
+		return ""
+	}
+	return best
+}
+
+// levenshteinDistance returns the edit distance between a and b.
+func levenshteinDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(curr[j-1]+1, minInt(prev[j]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if b < a {
+		return b
+	}
+	return a
+}
+
+// FormatDiagnostics renders diagnostics as one line per entry, suitable for
+// the `asdf config check` command to print directly: "<severity>
+// [<source>] <field>: <message>".
+func FormatDiagnostics(diagnostics []Diagnostic) string {
+	lines := make([]string, len(diagnostics))
+	for i, d := range diagnostics {
+		lines[i] = fmt.Sprintf("%s [%s] %s: %s", d.Severity, d.Source, d.Field, d.Message)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"encoding/json"
+	"io"
+)
+
+func init() {
+	RegisterDecoder(".json", jsonDecoder{})
+}
+
+// jsonRawSettings mirrors Settings but with pointer fields so we can tell
+// which keys were actually present in the document, and a loosely-typed
+// duration field since JSON allows `never` to be written as a bare string
+// and a minute count as a bare number.
+type jsonRawSettings struct {
+	LegacyVersionFile                 *bool             `json:"legacy_version_file"`
+	AlwaysKeepDownload                *bool             `json:"always_keep_download"`
+	PluginRepositoryLastCheckDuration interface{}       `json:"plugin_repository_last_check_duration"`
+	DisablePluginShortNameRepository  *bool             `json:"disable_plugin_short_name_repository"`
+	Concurrency                       *string           `json:"concurrency"`
+	Hooks                             map[string]string `json:"hooks"`
+	DefaultProfile                    *string           `json:"default_profile"`
+
+	// Profiles corresponds to a top-level "profiles" object, the JSON
+	// equivalent of the legacy format's `[profile "<name>"]` sections.
+	Profiles map[string]jsonRawProfile `json:"profiles"`
+}
+
+// jsonRawProfile is the JSON shape of a single named profile.
+type jsonRawProfile struct {
+	LegacyVersionFile                 *bool             `json:"legacy_version_file"`
+	AlwaysKeepDownload                *bool             `json:"always_keep_download"`
+	PluginRepositoryLastCheckDuration interface{}       `json:"plugin_repository_last_check_duration"`
+	DisablePluginShortNameRepository  *bool             `json:"disable_plugin_short_name_repository"`
+	Concurrency                       *string           `json:"concurrency"`
+	Hooks                             map[string]string `json:"hooks"`
+}
+
+// jsonDecoder parses a `.asdfrc.json` file into a Settings struct.
+type jsonDecoder struct{}
+
+func (jsonDecoder) Decode(r io.Reader, out *Settings) error {
+	var raw jsonRawSettings
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return err
+	}
+
+	if raw.LegacyVersionFile != nil {
+		out.LegacyVersionFile = *raw.LegacyVersionFile
+		out.Sources["legacy_version_file"] = SourceDefault
+	}
+	if raw.AlwaysKeepDownload != nil {
+		out.AlwaysKeepDownload = *raw.AlwaysKeepDownload
+		out.Sources["always_keep_download"] = SourceDefault
+	}
+	if raw.DisablePluginShortNameRepository != nil {
+		out.DisablePluginShortNameRepository = *raw.DisablePluginShortNameRepository
+		out.Sources["disable_plugin_short_name_repository"] = SourceDefault
+	}
+	if raw.Concurrency != nil {
+		out.Concurrency = *raw.Concurrency
+		out.Sources["concurrency"] = SourceDefault
+	}
+	if raw.PluginRepositoryLastCheckDuration != nil {
+		duration, err := decodeRawDuration(raw.PluginRepositoryLastCheckDuration)
+		if err != nil {
+			return err
+		}
+		out.PluginRepositoryLastCheckDuration = duration
+		out.Sources["plugin_repository_last_check_duration"] = SourceDefault
+	}
+	for name, cmd := range raw.Hooks {
+		out.Hooks[name] = cmd
+		out.Sources[name] = SourceDefault
+	}
+	if raw.DefaultProfile != nil {
+		out.DefaultProfile = *raw.DefaultProfile
+		out.Sources["default_profile"] = SourceDefault
+	}
+	for name, rawProfile := range raw.Profiles {
+		profile, err := buildProfileSettings(
+			rawProfile.LegacyVersionFile,
+			rawProfile.AlwaysKeepDownload,
+			rawProfile.DisablePluginShortNameRepository,
+			rawProfile.Concurrency,
+			rawProfile.PluginRepositoryLastCheckDuration,
+			rawProfile.Hooks,
+		)
+		if err != nil {
+			return err
+		}
+		out.Profiles[name] = profile
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"sort"
+)
+
+const defaultProfileName = "default"
+const profileEnvVar = "ASDF_PROFILE"
+
+// profileHeaderRegexp matches a `[profile "name"]` section header in the
+// legacy asdfrc format.
+var profileHeaderRegexp = regexp.MustCompile(`^\[profile\s+"([^"]+)"\]$`)
+
+// ProfileSettings holds the subset of Settings a named profile may
+// override. Pointer fields are nil when the profile doesn't set that key,
+// so the base Settings value shows through.
+type ProfileSettings struct {
+	LegacyVersionFile                 *bool
+	AlwaysKeepDownload                *bool
+	PluginRepositoryLastCheckDuration *PluginRepoCheckDuration
+	DisablePluginShortNameRepository  *bool
+	Concurrency                       *string
+	Hooks                             map[string]string
+
+	// Sources records which layer declared each hook in Hooks, the same way
+	// Settings.Sources does for the base settings, so diagnostics about a
+	// profile-scoped hook can point at the right asdfrc file.
+	Sources map[string]Source
+}
+
+func parseProfileHeader(line string) (string, bool) {
+	matches := profileHeaderRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		return "", false
+	}
+	return matches[1], true
+}
+
+// applyProfileSetting records a single `key = value` pair parsed inside a
+// [profile "name"] section onto profile, returning the updated value.
+// source is recorded for every key, not just hooks, so a diagnostic about a
+// profile-scoped concurrency or duration value can point at the asdfrc file
+// that actually set it, the same way Settings.Sources does for the base
+// settings.
+func applyProfileSetting(profile ProfileSettings, key, value string, source Source) (ProfileSettings, error) {
+	if profile.Sources == nil {
+		profile.Sources = map[string]Source{}
+	}
+
+	switch key {
+	case "legacy_version_file":
+		v := isTruthy(value)
+		profile.LegacyVersionFile = &v
+	case "always_keep_download":
+		v := isTruthy(value)
+		profile.AlwaysKeepDownload = &v
+	case "disable_plugin_short_name_repository":
+		v := isTruthy(value)
+		profile.DisablePluginShortNameRepository = &v
+	case "concurrency":
+		profile.Concurrency = &value
+	case "plugin_repository_last_check_duration":
+		duration, err := parsePluginRepoCheckDuration(value)
+		if err != nil {
+			return profile, err
+		}
+		profile.PluginRepositoryLastCheckDuration = &duration
+	default:
+		if profile.Hooks == nil {
+			profile.Hooks = map[string]string{}
+		}
+		profile.Hooks[key] = value
+	}
+	profile.Sources[key] = source
+
+	return profile, nil
+}
+
+// mergeProfiles overlays src's profiles onto dest, field by field, so a
+// project-level profile only needs to override the keys it cares about
+// while inheriting the rest from the same-named system/user profile.
+func mergeProfiles(dest, src map[string]ProfileSettings) {
+	for name, srcProfile := range src {
+		destProfile := dest[name]
+		if destProfile.Sources == nil {
+			destProfile.Sources = map[string]Source{}
+		}
+
+		if srcProfile.LegacyVersionFile != nil {
+			destProfile.LegacyVersionFile = srcProfile.LegacyVersionFile
+			destProfile.Sources["legacy_version_file"] = srcProfile.Sources["legacy_version_file"]
+		}
+		if srcProfile.AlwaysKeepDownload != nil {
+			destProfile.AlwaysKeepDownload = srcProfile.AlwaysKeepDownload
+			destProfile.Sources["always_keep_download"] = srcProfile.Sources["always_keep_download"]
+		}
+		if srcProfile.DisablePluginShortNameRepository != nil {
+			destProfile.DisablePluginShortNameRepository = srcProfile.DisablePluginShortNameRepository
+			destProfile.Sources["disable_plugin_short_name_repository"] = srcProfile.Sources["disable_plugin_short_name_repository"]
+		}
+		if srcProfile.Concurrency != nil {
+			destProfile.Concurrency = srcProfile.Concurrency
+			destProfile.Sources["concurrency"] = srcProfile.Sources["concurrency"]
+		}
+		if srcProfile.PluginRepositoryLastCheckDuration != nil {
+			destProfile.PluginRepositoryLastCheckDuration = srcProfile.PluginRepositoryLastCheckDuration
+			destProfile.Sources["plugin_repository_last_check_duration"] = srcProfile.Sources["plugin_repository_last_check_duration"]
+		}
+		if len(srcProfile.Hooks) > 0 {
+			if destProfile.Hooks == nil {
+				destProfile.Hooks = map[string]string{}
+			}
+			for hookName, cmd := range srcProfile.Hooks {
+				destProfile.Hooks[hookName] = cmd
+				destProfile.Sources[hookName] = srcProfile.Sources[hookName]
+			}
+		}
+
+		dest[name] = destProfile
+	}
+}
+
+// applyProfile resolves base against the named profile, falling through to
+// base's own values for anything the profile doesn't override. It returns
+// base unchanged when name isn't a known profile.
+func applyProfile(base Settings, name string) Settings {
+	profile, ok := base.Profiles[name]
+	if !ok {
+		return base
+	}
+
+	resolved := base
+
+	// Settings.Sources is a map, so the struct copy above still shares it
+	// with base (and whatever base.Sources is itself aliased to, e.g. the
+	// merged settings a caller is about to validate separately). Clone it
+	// before recording where the profile's overrides came from, or those
+	// writes would leak into base's own source attribution.
+	resolved.Sources = make(map[string]Source, len(base.Sources))
+	for key, source := range base.Sources {
+		resolved.Sources[key] = source
+	}
+
+	if profile.LegacyVersionFile != nil {
+		resolved.LegacyVersionFile = *profile.LegacyVersionFile
+		resolved.Sources["legacy_version_file"] = profile.Sources["legacy_version_file"]
+	}
+	if profile.AlwaysKeepDownload != nil {
+		resolved.AlwaysKeepDownload = *profile.AlwaysKeepDownload
+		resolved.Sources["always_keep_download"] = profile.Sources["always_keep_download"]
+	}
+	if profile.DisablePluginShortNameRepository != nil {
+		resolved.DisablePluginShortNameRepository = *profile.DisablePluginShortNameRepository
+		resolved.Sources["disable_plugin_short_name_repository"] = profile.Sources["disable_plugin_short_name_repository"]
+	}
+	if profile.Concurrency != nil {
+		resolved.Concurrency = *profile.Concurrency
+		resolved.Sources["concurrency"] = profile.Sources["concurrency"]
+		// A profile's raw value still needs to go through the same
+		// ASDF_CONCURRENCY/"auto" resolution the base settings already went
+		// through in loadMergedSettings, or the env override would be lost
+		// and "auto" would leak out unresolved whenever a profile sets it.
+		applyConcurrencyOverride(&resolved, resolved.Sources["concurrency"])
+	}
+	if profile.PluginRepositoryLastCheckDuration != nil {
+		resolved.PluginRepositoryLastCheckDuration = *profile.PluginRepositoryLastCheckDuration
+		resolved.Sources["plugin_repository_last_check_duration"] = profile.Sources["plugin_repository_last_check_duration"]
+	}
+	if len(profile.Hooks) > 0 {
+		merged := make(map[string]string, len(base.Hooks)+len(profile.Hooks))
+		for hookName, cmd := range base.Hooks {
+			merged[hookName] = cmd
+		}
+		for hookName, cmd := range profile.Hooks {
+			merged[hookName] = cmd
+		}
+		resolved.Hooks = merged
+	}
+
+	return resolved
+}
+
+// profileName resolves the active profile: $ASDF_PROFILE takes precedence,
+// then the default_profile asdfrc setting, then "default".
+func profileName(merged Settings) string {
+	if name := os.Getenv(profileEnvVar); name != "" {
+		return name
+	}
+	if merged.DefaultProfile != "" {
+		return merged.DefaultProfile
+	}
+	return defaultProfileName
+}
+
+// resolvedSettings returns the merged system/user/project settings with the
+// active profile's overrides already applied, used by every accessor
+// method so profile selection is transparent to callers.
+func (c Config) resolvedSettings() (Settings, error) {
+	merged, err := c.loadMergedSettings()
+	if err != nil {
+		return Settings{}, err
+	}
+	return applyProfile(merged, profileName(merged)), nil
+}
+
+// Profile returns the name of the currently active configuration profile.
+func (c Config) Profile() string {
+	merged, err := c.loadMergedSettings()
+	if err != nil {
+		return defaultProfileName
+	}
+	return profileName(merged)
+}
+
+// AvailableProfiles returns the names of every profile declared across the
+// system, user, and project asdfrc layers, sorted alphabetically.
+func (c Config) AvailableProfiles() []string {
+	merged, err := c.loadMergedSettings()
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(merged.Profiles))
+	for name := range merged.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
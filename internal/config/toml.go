@@ -0,0 +1,98 @@
+package config
+
+import (
+	"io"
+
+	"github.com/BurntSushi/toml"
+)
+
+func init() {
+	RegisterDecoder(".toml", tomlDecoder{})
+}
+
+// tomlRawSettings mirrors Settings but with pointer fields so we can tell
+// which keys were actually present in the document, and a loosely-typed
+// duration field since TOML allows `never` to be written as a bare string
+// and a minute count as a bare integer.
+type tomlRawSettings struct {
+	LegacyVersionFile                 *bool             `toml:"legacy_version_file"`
+	AlwaysKeepDownload                *bool             `toml:"always_keep_download"`
+	PluginRepositoryLastCheckDuration interface{}       `toml:"plugin_repository_last_check_duration"`
+	DisablePluginShortNameRepository  *bool             `toml:"disable_plugin_short_name_repository"`
+	Concurrency                       *string           `toml:"concurrency"`
+	Hooks                             map[string]string `toml:"hooks"`
+	DefaultProfile                    *string           `toml:"default_profile"`
+
+	// Profiles corresponds to `[profile."<name>"]` tables, the TOML
+	// equivalent of the legacy format's `[profile "<name>"]` sections.
+	Profiles map[string]tomlRawProfile `toml:"profile"`
+}
+
+// tomlRawProfile is the TOML shape of a single named profile.
+type tomlRawProfile struct {
+	LegacyVersionFile                 *bool             `toml:"legacy_version_file"`
+	AlwaysKeepDownload                *bool             `toml:"always_keep_download"`
+	PluginRepositoryLastCheckDuration interface{}       `toml:"plugin_repository_last_check_duration"`
+	DisablePluginShortNameRepository  *bool             `toml:"disable_plugin_short_name_repository"`
+	Concurrency                       *string           `toml:"concurrency"`
+	Hooks                             map[string]string `toml:"hooks"`
+}
+
+// tomlDecoder parses a `.asdfrc.toml` file into a Settings struct.
+type tomlDecoder struct{}
+
+func (tomlDecoder) Decode(r io.Reader, out *Settings) error {
+	var raw tomlRawSettings
+	if _, err := toml.NewDecoder(r).Decode(&raw); err != nil {
+		return err
+	}
+
+	if raw.LegacyVersionFile != nil {
+		out.LegacyVersionFile = *raw.LegacyVersionFile
+		out.Sources["legacy_version_file"] = SourceDefault
+	}
+	if raw.AlwaysKeepDownload != nil {
+		out.AlwaysKeepDownload = *raw.AlwaysKeepDownload
+		out.Sources["always_keep_download"] = SourceDefault
+	}
+	if raw.DisablePluginShortNameRepository != nil {
+		out.DisablePluginShortNameRepository = *raw.DisablePluginShortNameRepository
+		out.Sources["disable_plugin_short_name_repository"] = SourceDefault
+	}
+	if raw.Concurrency != nil {
+		out.Concurrency = *raw.Concurrency
+		out.Sources["concurrency"] = SourceDefault
+	}
+	if raw.PluginRepositoryLastCheckDuration != nil {
+		duration, err := decodeRawDuration(raw.PluginRepositoryLastCheckDuration)
+		if err != nil {
+			return err
+		}
+		out.PluginRepositoryLastCheckDuration = duration
+		out.Sources["plugin_repository_last_check_duration"] = SourceDefault
+	}
+	for name, cmd := range raw.Hooks {
+		out.Hooks[name] = cmd
+		out.Sources[name] = SourceDefault
+	}
+	if raw.DefaultProfile != nil {
+		out.DefaultProfile = *raw.DefaultProfile
+		out.Sources["default_profile"] = SourceDefault
+	}
+	for name, rawProfile := range raw.Profiles {
+		profile, err := buildProfileSettings(
+			rawProfile.LegacyVersionFile,
+			rawProfile.AlwaysKeepDownload,
+			rawProfile.DisablePluginShortNameRepository,
+			rawProfile.Concurrency,
+			rawProfile.PluginRepositoryLastCheckDuration,
+			rawProfile.Hooks,
+		)
+		if err != nil {
+			return err
+		}
+		out.Profiles[name] = profile
+	}
+
+	return nil
+}